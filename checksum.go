@@ -0,0 +1,152 @@
+package deadigations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"runtime"
+)
+
+// ErrChecksumDrift is returned by MigrateUp when a previously-applied
+// migration's checksum no longer matches what was recorded when it ran.
+var ErrChecksumDrift = errors.New("migration checksum drift detected")
+
+type migrationChecksumRow struct {
+	Checksum string
+}
+
+// computeChecksum returns migration.Checksum if the caller supplied one, or
+// else a SHA-256 hash derived from where Migrate is defined. Go doesn't
+// expose a function's literal source at runtime, so the file and line of
+// Migrate's entry point stand in for "the migration's body" - moving the
+// func without changing its behavior will still register as drift.
+func computeChecksum(migration Migration) string {
+	if migration.Checksum != "" {
+		return migration.Checksum
+	}
+
+	fn := runtime.FuncForPC(reflect.ValueOf(migration.Migrate).Pointer())
+	file, line := fn.FileLine(fn.Entry())
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%d", fn.Name(), file, line)))
+	return hex.EncodeToString(sum[:])
+}
+
+// hasMigrationsTable reports whether the migrations table exists yet. On a
+// fresh database it doesn't until gormigrate's first Migrate() call creates
+// it, so callers must skip checksum bookkeeping that reads or alters the
+// table until after that first run.
+func (m *MigrationTool) hasMigrationsTable() bool {
+	return m.db.Migrator().HasTable(m.options.TableName)
+}
+
+// ensureChecksumColumn adds the checksum column to the migrations table if
+// it isn't there yet.
+func (m *MigrationTool) ensureChecksumColumn() error {
+	migrator := m.db.Table(m.options.TableName).Migrator()
+	if migrator.HasColumn(&migrationChecksumRow{}, "Checksum") {
+		return nil
+	}
+	return migrator.AddColumn(&migrationChecksumRow{}, "Checksum")
+}
+
+// storedChecksums returns the checksum recorded for every currently applied
+// migration, keyed by ID.
+func (m *MigrationTool) storedChecksums() (map[string]string, error) {
+	rows := []struct {
+		ID       string
+		Checksum string
+	}{}
+	selectCols := fmt.Sprintf("%s AS id, checksum", m.options.IDColumnName)
+	if err := m.db.Table(m.options.TableName).Select(selectCols).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	checksums := make(map[string]string, len(rows))
+	for _, row := range rows {
+		checksums[row.ID] = row.Checksum
+	}
+	return checksums, nil
+}
+
+// verifyChecksums compares the checksum recorded for every applied
+// migration against what it computes to today, failing loudly on a
+// mismatch unless allowDrift is set.
+func (m *MigrationTool) verifyChecksums(allowDrift bool) error {
+	stored, err := m.storedChecksums()
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range m.allMigrations() {
+		storedSum, applied := stored[migration.ID]
+		if !applied || storedSum == "" {
+			continue
+		}
+
+		currentSum := computeChecksum(migration)
+		if storedSum == currentSum {
+			continue
+		}
+
+		if allowDrift {
+			log.Printf("Checksum drift detected for migration %s, continuing because allow-drift is set", migration.ID)
+			continue
+		}
+
+		return fmt.Errorf("%w: migration %s was applied with checksum %s but now computes to %s", ErrChecksumDrift, migration.ID, storedSum, currentSum)
+	}
+
+	return nil
+}
+
+// recordNewChecksums records the checksum for every migration present in
+// postApplied but absent from preApplied. It's shared by every command that
+// can apply new migrations (MigrateUp, MigrateSteps, Redo), so
+// verifyChecksums never skips one just because it wasn't applied through
+// -up.
+func (m *MigrationTool) recordNewChecksums(preApplied map[string]bool) error {
+	postApplied, err := m.appliedIDs()
+	if err != nil {
+		return err
+	}
+
+	newlyApplied := make([]string, 0)
+	for id := range postApplied {
+		if !preApplied[id] {
+			newlyApplied = append(newlyApplied, id)
+		}
+	}
+	return m.recordChecksums(newlyApplied)
+}
+
+// recordChecksums stores each migration's current checksum against its row
+// in the migrations table.
+func (m *MigrationTool) recordChecksums(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	all := m.allMigrations()
+	byID := make(map[string]Migration, len(all))
+	for _, migration := range all {
+		byID[migration.ID] = migration
+	}
+
+	for _, id := range ids {
+		migration, ok := byID[id]
+		if !ok {
+			continue
+		}
+		checksum := computeChecksum(migration)
+		err := m.db.Table(m.options.TableName).
+			Where(m.options.IDColumnName+" = ?", id).
+			Update("checksum", checksum).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
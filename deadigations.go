@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/go-gormigrate/gormigrate/v2"
@@ -18,55 +18,73 @@ type Migration struct {
 	Description string
 	Migrate     func(tx *gorm.DB) error
 	Rollback    func(tx *gorm.DB) error
+
+	// Checksum optionally pins the checksum drift check (see MigrateUp) to a
+	// caller-supplied value instead of one derived from Migrate's source
+	// location. Leave empty unless you need a stable checksum across
+	// refactors that only move code around.
+	Checksum string
 }
 
-var (
-	once                 sync.Once
-	instance             *MigrationTool
-	registeredMigrations []*gormigrate.Migration
-)
+var registeredMigrations []Migration
 
 func RegisterMigration(migration Migration) {
-	gormMigration := &gormigrate.Migration{
-		ID:       migration.ID,
-		Migrate:  migration.Migrate,
-		Rollback: migration.Rollback,
-	}
-	registeredMigrations = append(registeredMigrations, gormMigration)
+	registeredMigrations = append(registeredMigrations, migration)
 }
 
 type MigrationTool struct {
 	db      *gorm.DB
-	options *gormigrate.Options
+	dialect string
+	options *Options
 }
 
-// Ensures only a single instance of the tool is created.
-func NewMigrationTool(dsn string) *MigrationTool {
-	once.Do(func() {
-		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
-		if err != nil {
-			log.Fatalf("Failed to connect to the database: %v", err)
-		}
+// NewMigrationTool opens a Postgres connection for dsn and returns a new
+// MigrationTool. Each call creates an independent instance, so tests and
+// multi-tenant apps are free to create as many as they need.
+func NewMigrationTool(dsn string, opts ...Option) *MigrationTool {
+	return NewMigrationToolWithDialector(postgres.Open(dsn), opts...)
+}
 
-		instance = &MigrationTool{
-			db: db,
-			options: &gormigrate.Options{
-				TableName:                 "migrations",
-				IDColumnName:              "id",
-				IDColumnSize:              255,
-				UseTransaction:            true,
-				ValidateUnknownMigrations: false,
-			},
-		}
-	})
+// NewMigrationToolWithDialector builds a MigrationTool from any gorm
+// Dialector (postgres.Open, mysql.Open, sqlite.Open, sqlserver.Open, ...),
+// so callers are not locked into a single database driver.
+func NewMigrationToolWithDialector(dialector gorm.Dialector, opts ...Option) *MigrationTool {
+	options := defaultOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
 
-	return instance
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		log.Fatalf("Failed to connect to the database: %v", err)
+	}
+
+	return &MigrationTool{
+		db:      db,
+		dialect: dialector.Name(),
+		options: options,
+	}
+}
+
+// gormigrateOptions translates the tool's own Options into the Options type
+// gormigrate expects.
+func (m *MigrationTool) gormigrateOptions() *gormigrate.Options {
+	return &gormigrate.Options{
+		TableName:                 m.options.TableName,
+		IDColumnName:              m.options.IDColumnName,
+		IDColumnSize:              m.options.IDColumnSize,
+		UseTransaction:            m.options.UseTransaction,
+		ValidateUnknownMigrations: m.options.ValidateUnknownMigrations,
+	}
 }
 
 func (m *MigrationTool) Run(args []string) {
 	if len(args) > 1 {
 		switch args[1] {
 		case "-up":
+			if len(args) > 2 && args[2] == "-allow-drift" {
+				m.options.AllowDrift = true
+			}
 			if err := m.MigrateUp(); err != nil {
 				log.Fatalf("Migration failed: %v", err)
 			}
@@ -74,6 +92,71 @@ func (m *MigrationTool) Run(args []string) {
 			if err := m.MigrateDown(); err != nil {
 				log.Fatalf("Rollback failed: %v", err)
 			}
+		case "-status":
+			if err := m.printStatus(); err != nil {
+				log.Fatalf("Failed to get status: %v", err)
+			}
+		case "-version":
+			if err := m.printVersion(); err != nil {
+				log.Fatalf("Failed to get version: %v", err)
+			}
+		case "-history":
+			if err := m.printHistory(); err != nil {
+				log.Fatalf("Failed to get history: %v", err)
+			}
+		case "-down-to":
+			if len(args) < 3 {
+				log.Fatal("Please provide a migration ID to roll back to")
+			}
+			if err := m.MigrateDownTo(args[2]); err != nil {
+				log.Fatalf("Rollback failed: %v", err)
+			}
+		case "-steps":
+			if len(args) < 3 {
+				log.Fatal("Please provide a step count, e.g. -steps 2 or -steps -1")
+			}
+			steps, err := strconv.Atoi(args[2])
+			if err != nil {
+				log.Fatalf("Invalid step count %q: %v", args[2], err)
+			}
+			if err := m.MigrateSteps(steps); err != nil {
+				log.Fatalf("Migration failed: %v", err)
+			}
+		case "-redo":
+			if err := m.Redo(); err != nil {
+				log.Fatalf("Redo failed: %v", err)
+			}
+		case "-start":
+			if len(args) < 3 {
+				log.Fatal("Please provide an expand/contract migration ID")
+			}
+			if err := m.Start(args[2]); err != nil {
+				log.Fatalf("Start failed: %v", err)
+			}
+		case "-complete":
+			if len(args) < 3 {
+				log.Fatal("Please provide an expand/contract migration ID")
+			}
+			if err := m.Complete(args[2]); err != nil {
+				log.Fatalf("Complete failed: %v", err)
+			}
+		case "-rollback":
+			if len(args) < 3 {
+				log.Fatal("Please provide an expand/contract migration ID")
+			}
+			if err := m.RollbackExpandContract(args[2]); err != nil {
+				log.Fatalf("Rollback failed: %v", err)
+			}
+		case "-latest-version":
+			version, err := m.LatestExpandContractVersion()
+			if err != nil {
+				log.Fatalf("Failed to get latest version: %v", err)
+			}
+			if version == "" {
+				fmt.Println("No expand/contract migrations completed")
+			} else {
+				fmt.Println(version)
+			}
 		case "-create":
 			if len(args) < 3 {
 				log.Fatal("Please provide a name for the migration")
@@ -90,36 +173,87 @@ func (m *MigrationTool) Run(args []string) {
 			if err := m.CreateTransactionMigrationFile(migrationName); err != nil {
 				log.Fatalf("Failed to create transaction migration file: %v", err)
 			}
+		case "-create-sql":
+			if len(args) < 3 {
+				log.Fatal("Please provide a name for the SQL migration")
+			}
+			migrationName := args[2]
+			if err := m.CreateSQLMigrationFile(migrationName); err != nil {
+				log.Fatalf("Failed to create SQL migration files: %v", err)
+			}
 		default:
-			log.Fatal("Invalid command. Use -up, -down, -create, or -create-tx")
+			log.Fatal("Invalid command. Use -up, -down, -down-to, -steps, -redo, -status, -version, -history, -start, -complete, -rollback, -latest-version, -create, -create-tx, or -create-sql")
 		}
 	} else {
-		log.Println("No command provided. Use -up, -down, -create, or -create-tx")
+		log.Println("No command provided. Use -up, -down, -down-to, -steps, -redo, -status, -version, -history, -start, -complete, -rollback, -latest-version, -create, -create-tx, or -create-sql")
 	}
 }
 
 func (m *MigrationTool) MigrateUp() error {
-	if len(registeredMigrations) == 0 {
+	if len(m.allMigrations()) == 0 {
 		log.Println("No migrations registered")
 		return nil
 	}
 
-	migrator := gormigrate.New(m.db, m.options, registeredMigrations)
+	return m.withLock(m.migrateUp)
+}
+
+func (m *MigrationTool) migrateUp() error {
+	if err := m.ensureHistoryTable(); err != nil {
+		return err
+	}
+
+	// The migrations table itself doesn't exist yet on a fresh database -
+	// gormigrate's Migrate() below creates it - so there's nothing to
+	// checksum-check or read applied IDs from until after that first run.
+	preApplied := make(map[string]bool)
+	if m.hasMigrationsTable() {
+		if err := m.ensureChecksumColumn(); err != nil {
+			return err
+		}
+		if err := m.verifyChecksums(m.options.AllowDrift); err != nil {
+			return err
+		}
+
+		var err error
+		preApplied, err = m.appliedIDs()
+		if err != nil {
+			return err
+		}
+	}
+
+	migrator := gormigrate.New(m.db, m.gormigrateOptions(), m.buildGormigrateMigrations())
 
 	if err := migrator.Migrate(); err != nil {
 		return err
 	}
+
+	if err := m.ensureChecksumColumn(); err != nil {
+		return err
+	}
+	if err := m.recordNewChecksums(preApplied); err != nil {
+		return err
+	}
+
 	log.Println("Migrations applied successfully!")
 	return nil
 }
 
 func (m *MigrationTool) MigrateDown() error {
-	if len(registeredMigrations) == 0 {
+	if len(m.allMigrations()) == 0 {
 		log.Println("No migrations registered")
 		return nil
 	}
 
-	migrator := gormigrate.New(m.db, m.options, registeredMigrations)
+	return m.withLock(m.migrateDown)
+}
+
+func (m *MigrationTool) migrateDown() error {
+	if err := m.ensureHistoryTable(); err != nil {
+		return err
+	}
+
+	migrator := gormigrate.New(m.db, m.gormigrateOptions(), m.buildGormigrateMigrations())
 
 	if err := migrator.RollbackLast(); err != nil {
 		return err
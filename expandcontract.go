@@ -0,0 +1,310 @@
+package deadigations
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Expand/contract phase names, persisted in the migration_phases table.
+const (
+	PhaseInProgressStart = "in_progress_start"
+	PhaseCompletedStart  = "completed_start"
+	PhaseCompleted       = "completed"
+)
+
+// ErrExpandContractInProgress is returned by Start when another
+// ExpandContractMigration is already in its Start phase; only one may run
+// at a time so that Complete always knows which expand it is closing out.
+var ErrExpandContractInProgress = errors.New("another expand/contract migration is in progress")
+
+// ExpandContractMigration lets a schema change roll out without locking
+// tables or breaking older running app instances, following the pgroll
+// start/complete/rollback pattern:
+//
+//   - Start adds the new schema alongside the old one and backfills data,
+//     typically via triggers or views, so both old and new app versions
+//     keep working.
+//   - Complete drops the old columns/tables once every instance has
+//     rolled over to the new schema.
+//   - Rollback undoes Start, and is only valid before Complete has run.
+type ExpandContractMigration struct {
+	ID          string
+	Description string
+	Start       func(tx *gorm.DB) error
+	Complete    func(tx *gorm.DB) error
+	Rollback    func(tx *gorm.DB) error
+}
+
+var registeredExpandContractMigrations []ExpandContractMigration
+
+// RegisterExpandContractMigration registers an expand/contract migration,
+// mirroring RegisterMigration.
+func RegisterExpandContractMigration(migration ExpandContractMigration) {
+	registeredExpandContractMigrations = append(registeredExpandContractMigrations, migration)
+}
+
+// MigrationPhase is the persisted phase state for an ExpandContractMigration.
+type MigrationPhase struct {
+	ID          uint   `gorm:"primaryKey"`
+	MigrationID string `gorm:"column:migration_id;uniqueIndex"`
+	Phase       string
+	StartedAt   time.Time
+	CompletedAt *time.Time
+}
+
+func (MigrationPhase) TableName() string {
+	return "migration_phases"
+}
+
+func (m *MigrationTool) ensurePhaseTable() error {
+	return m.db.AutoMigrate(&MigrationPhase{})
+}
+
+// expandContractLockKeyOffset distinguishes the expand/contract Start lock
+// from the caller's general migration lock key (see Options.LockKey):
+// XORing it in rather than using a fixed offset means it still varies with
+// WithLockKey, so two independently-configured apps sharing a database
+// don't collide just because one picked the other's key plus a constant.
+const expandContractLockKeyOffset int64 = 0x65634c6b6c6f636b // "ecLklock" in ASCII hex
+
+func (m *MigrationTool) expandContractLockKey() int64 {
+	return m.options.LockKey ^ expandContractLockKeyOffset
+}
+
+// withExpandContractLock runs fn while holding the lock that enforces "only
+// one expand/contract migration may be mid-Start at a time". For Postgres
+// and MySQL it uses the same session-scoped advisory lock approach as
+// withLock - acquired and released on a single pinned connection via
+// db.Connection - so a crash or panic mid-Start releases it automatically
+// when the connection closes instead of stranding a row that needs manual
+// cleanup. Unlike withLock, acquisition never blocks: a second concurrent
+// Start fails immediately with ErrExpandContractInProgress.
+//
+// Dialects without an advisory lock primitive fall back to a best-effort,
+// non-atomic phase-table check, same as withLock falls back to no
+// cluster-wide lock at all for those dialects.
+func (m *MigrationTool) withExpandContractLock(id string, fn func() error) error {
+	key := m.expandContractLockKey()
+	switch m.dialect {
+	case "postgres":
+		return m.db.Connection(func(tx *gorm.DB) error {
+			var acquired bool
+			if err := tx.Raw("SELECT pg_try_advisory_lock(?)", key).Scan(&acquired).Error; err != nil {
+				return err
+			}
+			if !acquired {
+				return ErrExpandContractInProgress
+			}
+			defer m.releaseLock(tx, "SELECT pg_advisory_unlock(?)", key)
+			return fn()
+		})
+	case "mysql":
+		lockName := fmt.Sprintf("deadigations:expand-contract:%d", key)
+		return m.db.Connection(func(tx *gorm.DB) error {
+			var acquired *int64
+			if err := tx.Raw("SELECT GET_LOCK(?, 0)", lockName).Scan(&acquired).Error; err != nil {
+				return err
+			}
+			if acquired == nil || *acquired != 1 {
+				return ErrExpandContractInProgress
+			}
+			defer m.releaseLock(tx, "SELECT RELEASE_LOCK(?)", lockName)
+			return fn()
+		})
+	default:
+		log.Printf("No advisory lock support for dialect %q, falling back to a best-effort Start concurrency check", m.dialect)
+		if err := m.checkNoOtherStartInProgress(id); err != nil {
+			return err
+		}
+		return fn()
+	}
+}
+
+// checkNoOtherStartInProgress is the fallback guard used for dialects with
+// no advisory lock primitive. It's a plain SELECT for a committed
+// in_progress_start row, but Start writes that phase and completed_start
+// inside one transaction, so the SELECT does not see another Start's
+// in-progress row and the check does not stop two concurrent Start calls
+// for different IDs.
+func (m *MigrationTool) checkNoOtherStartInProgress(id string) error {
+	var inProgress MigrationPhase
+	err := m.db.Where("phase = ?", PhaseInProgressStart).First(&inProgress).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if inProgress.MigrationID != id {
+		return fmt.Errorf("%w: %s", ErrExpandContractInProgress, inProgress.MigrationID)
+	}
+	return nil
+}
+
+func (m *MigrationTool) findExpandContractMigration(id string) (*ExpandContractMigration, error) {
+	for _, migration := range registeredExpandContractMigrations {
+		if migration.ID == id {
+			return &migration, nil
+		}
+	}
+	return nil, fmt.Errorf("no expand/contract migration registered with ID %s", id)
+}
+
+func (m *MigrationTool) phaseFor(id string) (*MigrationPhase, error) {
+	var phase MigrationPhase
+	err := m.db.Where("migration_id = ?", id).First(&phase).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &phase, nil
+}
+
+// Start runs an ExpandContractMigration's additive Start phase. Only one
+// expand/contract migration may be mid-Start at a time, so Complete and
+// Rollback always have an unambiguous target.
+func (m *MigrationTool) Start(id string) error {
+	if err := m.ensurePhaseTable(); err != nil {
+		return err
+	}
+
+	migration, err := m.findExpandContractMigration(id)
+	if err != nil {
+		return err
+	}
+
+	if err := m.checkNotStarted(id); err != nil {
+		return err
+	}
+
+	return m.withExpandContractLock(id, func() error {
+		// Re-check now that the lock is held: another Start could have run
+		// to completion for this id between the check above and acquiring
+		// it.
+		if err := m.checkNotStarted(id); err != nil {
+			return err
+		}
+
+		// The phase row, migration.Start, and the phase-complete update all
+		// run in one transaction so a failing Start can't strand the row in
+		// in_progress_start: a rollback here undoes the row along with it.
+		startedAt := time.Now()
+		if err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&MigrationPhase{MigrationID: id, Phase: PhaseInProgressStart, StartedAt: startedAt}).Error; err != nil {
+				return err
+			}
+			if err := migration.Start(tx); err != nil {
+				return err
+			}
+			return tx.Model(&MigrationPhase{}).Where("migration_id = ?", id).Update("phase", PhaseCompletedStart).Error
+		}); err != nil {
+			return err
+		}
+
+		log.Printf("Started expand/contract migration %s", id)
+		return nil
+	})
+}
+
+// checkNotStarted returns an error if id already has a phase recorded,
+// i.e. some Start call for it has already run (successfully or not).
+func (m *MigrationTool) checkNotStarted(id string) error {
+	existing, err := m.phaseFor(id)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.Phase != "" {
+		return fmt.Errorf("expand/contract migration %s already started (phase: %s)", id, existing.Phase)
+	}
+	return nil
+}
+
+// Complete runs an ExpandContractMigration's contract phase, dropping the
+// old schema. The migration must already be in the completed_start phase.
+func (m *MigrationTool) Complete(id string) error {
+	migration, err := m.findExpandContractMigration(id)
+	if err != nil {
+		return err
+	}
+
+	phase, err := m.phaseFor(id)
+	if err != nil {
+		return err
+	}
+	if phase == nil || phase.Phase != PhaseCompletedStart {
+		return fmt.Errorf("expand/contract migration %s must be in phase %s before it can be completed", id, PhaseCompletedStart)
+	}
+
+	// migration.Complete and the phase update run in one transaction, same
+	// as Start, so a crash between them can't strand the row at
+	// completed_start after the schema change has already committed.
+	if err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := migration.Complete(tx); err != nil {
+			return err
+		}
+		completedAt := time.Now()
+		return tx.Model(&MigrationPhase{}).Where("migration_id = ?", id).
+			Updates(map[string]interface{}{"phase": PhaseCompleted, "completed_at": completedAt}).Error
+	}); err != nil {
+		return err
+	}
+
+	log.Printf("Completed expand/contract migration %s", id)
+	return nil
+}
+
+// RollbackExpandContract undoes an ExpandContractMigration's Start phase.
+// It is only valid before Complete has run.
+func (m *MigrationTool) RollbackExpandContract(id string) error {
+	migration, err := m.findExpandContractMigration(id)
+	if err != nil {
+		return err
+	}
+
+	phase, err := m.phaseFor(id)
+	if err != nil {
+		return err
+	}
+	if phase == nil || phase.Phase == PhaseCompleted {
+		return fmt.Errorf("expand/contract migration %s has no in-flight Start to roll back", id)
+	}
+
+	// migration.Rollback and deleting the phase row run in one transaction,
+	// same as Start and Complete, so a crash between them can't leave the
+	// row pointing at a Start that's already been undone.
+	if err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := migration.Rollback(tx); err != nil {
+			return err
+		}
+		return tx.Where("migration_id = ?", id).Delete(&MigrationPhase{}).Error
+	}); err != nil {
+		return err
+	}
+
+	log.Printf("Rolled back expand/contract migration %s", id)
+	return nil
+}
+
+// LatestExpandContractVersion returns the ID of the most recently completed
+// expand/contract migration, or "" if none have completed yet.
+func (m *MigrationTool) LatestExpandContractVersion() (string, error) {
+	if err := m.ensurePhaseTable(); err != nil {
+		return "", err
+	}
+
+	var phase MigrationPhase
+	err := m.db.Where("phase = ?", PhaseCompleted).Order("completed_at DESC").First(&phase).Error
+	if err == gorm.ErrRecordNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return phase.MigrationID, nil
+}
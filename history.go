@@ -0,0 +1,229 @@
+package deadigations
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// MigrationHistoryEntry is a single row of the audit trail recorded every
+// time a migration is applied or rolled back. Unlike the migrations table,
+// which only tracks the current state, this table keeps every run.
+type MigrationHistoryEntry struct {
+	ID          uint   `gorm:"primaryKey"`
+	MigrationID string `gorm:"column:migration_id;index"`
+	AppliedAt   time.Time
+	Direction   string // "up" or "down"
+	DurationMs  int64
+	Checksum    string
+}
+
+func (MigrationHistoryEntry) TableName() string {
+	return "migration_history"
+}
+
+// MigrationStatus describes a single registered migration's applied state,
+// as reported by -status.
+type MigrationStatus struct {
+	ID          string
+	Description string
+	Applied     bool
+	AppliedAt   *time.Time
+}
+
+func (m *MigrationTool) ensureHistoryTable() error {
+	return m.db.AutoMigrate(&MigrationHistoryEntry{})
+}
+
+// buildGormigrateMigrations converts the registered Migrations into the
+// gormigrate representation, wrapping each Migrate/Rollback so that every
+// run is timed and recorded to the migration_history table.
+func (m *MigrationTool) buildGormigrateMigrations() []*gormigrate.Migration {
+	all := m.allMigrations()
+	migrations := make([]*gormigrate.Migration, 0, len(all))
+	for _, migration := range all {
+		migration := migration
+		checksum := computeChecksum(migration)
+		migrations = append(migrations, &gormigrate.Migration{
+			ID: migration.ID,
+			Migrate: func(tx *gorm.DB) error {
+				return m.runAndRecord(migration.ID, checksum, "up", func() error {
+					return migration.Migrate(tx)
+				})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return m.runAndRecord(migration.ID, checksum, "down", func() error {
+					return migration.Rollback(tx)
+				})
+			},
+		})
+	}
+	return migrations
+}
+
+// runAndRecord runs fn, timing it, and writes a MigrationHistoryEntry
+// regardless of outcome so failed runs show up in -history too. checksum is
+// the migration's checksum at the time it ran, recorded alongside the entry
+// so -history shows what was actually applied even if the migration's body
+// drifts later.
+func (m *MigrationTool) runAndRecord(migrationID, checksum, direction string, fn func() error) error {
+	start := time.Now()
+	runErr := fn()
+	duration := time.Since(start)
+
+	entry := MigrationHistoryEntry{
+		MigrationID: migrationID,
+		AppliedAt:   start,
+		Direction:   direction,
+		DurationMs:  duration.Milliseconds(),
+		Checksum:    checksum,
+	}
+	if err := m.db.Create(&entry).Error; err != nil {
+		log.Printf("Failed to record migration history for %s: %v", migrationID, err)
+	}
+
+	return runErr
+}
+
+// appliedIDs returns the set of migration IDs currently recorded as applied
+// in the migrations table.
+func (m *MigrationTool) appliedIDs() (map[string]bool, error) {
+	var ids []string
+	if err := m.db.Table(m.options.TableName).Pluck(m.options.IDColumnName, &ids).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		applied[id] = true
+	}
+	return applied, nil
+}
+
+// lastAppliedAt returns the timestamp of the most recent "up" entry for the
+// given migration ID, if any.
+func (m *MigrationTool) lastAppliedAt(migrationID string) (*time.Time, error) {
+	var entry MigrationHistoryEntry
+	err := m.db.
+		Where("migration_id = ? AND direction = ?", migrationID, "up").
+		Order("applied_at DESC").
+		First(&entry).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry.AppliedAt, nil
+}
+
+// Status reports the applied/pending state of every registered migration.
+func (m *MigrationTool) Status() ([]MigrationStatus, error) {
+	if err := m.ensureHistoryTable(); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.appliedIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	all := m.allMigrations()
+	statuses := make([]MigrationStatus, 0, len(all))
+	for _, migration := range all {
+		status := MigrationStatus{
+			ID:          migration.ID,
+			Description: migration.Description,
+			Applied:     applied[migration.ID],
+		}
+		if status.Applied {
+			appliedAt, err := m.lastAppliedAt(migration.ID)
+			if err != nil {
+				return nil, err
+			}
+			status.AppliedAt = appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Version returns the ID of the most recently applied migration, or "" if
+// none have been applied yet.
+func (m *MigrationTool) Version() (string, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return "", err
+	}
+
+	var version string
+	var latest time.Time
+	for _, status := range statuses {
+		if status.Applied && status.AppliedAt != nil && status.AppliedAt.After(latest) {
+			latest = *status.AppliedAt
+			version = status.ID
+		}
+	}
+	return version, nil
+}
+
+// History returns the full migration_history audit trail, oldest first.
+func (m *MigrationTool) History() ([]MigrationHistoryEntry, error) {
+	if err := m.ensureHistoryTable(); err != nil {
+		return nil, err
+	}
+
+	var entries []MigrationHistoryEntry
+	if err := m.db.Order("applied_at ASC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (m *MigrationTool) printStatus() error {
+	statuses, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		state := "pending"
+		appliedAt := ""
+		if status.Applied {
+			state = "applied"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+		}
+		fmt.Printf("%-30s %-8s %s\n", status.ID, state, appliedAt)
+	}
+	return nil
+}
+
+func (m *MigrationTool) printVersion() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if version == "" {
+		fmt.Println("No migrations applied")
+		return nil
+	}
+	fmt.Println(version)
+	return nil
+}
+
+func (m *MigrationTool) printHistory() error {
+	entries, err := m.History()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		fmt.Printf("%-30s %-4s %-30s %dms %s\n", entry.MigrationID, entry.Direction, entry.AppliedAt.Format(time.RFC3339), entry.DurationMs, entry.Checksum)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package deadigations
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// defaultLockKey is an arbitrary, fixed identifier so that every process
+// migrating the same database targets the same advisory lock by default.
+const defaultLockKey int64 = 850276547
+
+// lockPollInterval is how often an in-progress, timed lock acquisition
+// attempt is retried.
+const lockPollInterval = 100 * time.Millisecond
+
+// ErrMigrationLocked is returned by MigrateUp/MigrateDown when LockTimeout
+// is set and another process is still holding the migration lock once it
+// elapses.
+var ErrMigrationLocked = errors.New("migration lock is held by another process")
+
+// withLock runs fn while holding the cluster-wide migration lock, so that
+// when several replicas boot at once and each call MigrateUp/MigrateDown,
+// only one of them actually runs migrations.
+//
+// Postgres and MySQL advisory locks are scoped to the session (connection)
+// that took them, so acquire and release must run on the very same
+// connection - handing them to gorm's pool independently risks the release
+// landing on a different pooled conn, returning false, and leaking the lock
+// for the life of whichever backend actually holds it. db.Connection pins a
+// single *sql.Conn for the duration of the closure to guarantee that.
+func (m *MigrationTool) withLock(fn func() error) error {
+	switch m.dialect {
+	case "postgres":
+		return m.db.Connection(func(tx *gorm.DB) error {
+			if err := m.acquirePostgresLock(tx); err != nil {
+				return err
+			}
+			defer m.releaseLock(tx, "SELECT pg_advisory_unlock(?)", m.options.LockKey)
+			return fn()
+		})
+	case "mysql":
+		lockName := fmt.Sprintf("deadigations:%d", m.options.LockKey)
+		return m.db.Connection(func(tx *gorm.DB) error {
+			if err := m.acquireMySQLLock(tx, lockName); err != nil {
+				return err
+			}
+			defer m.releaseLock(tx, "SELECT RELEASE_LOCK(?)", lockName)
+			return fn()
+		})
+	default:
+		log.Printf("No advisory lock support for dialect %q, proceeding without a cluster-wide lock", m.dialect)
+		return fn()
+	}
+}
+
+func (m *MigrationTool) releaseLock(tx *gorm.DB, query string, args ...interface{}) {
+	if err := tx.Exec(query, args...).Error; err != nil {
+		log.Printf("Failed to release migration lock: %v", err)
+	}
+}
+
+func (m *MigrationTool) acquirePostgresLock(tx *gorm.DB) error {
+	if m.options.LockTimeout <= 0 {
+		return tx.Exec("SELECT pg_advisory_lock(?)", m.options.LockKey).Error
+	}
+
+	deadline := time.Now().Add(m.options.LockTimeout)
+	for {
+		var acquired bool
+		if err := tx.Raw("SELECT pg_try_advisory_lock(?)", m.options.LockKey).Scan(&acquired).Error; err != nil {
+			return err
+		}
+		if acquired {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w: postgres advisory lock %d", ErrMigrationLocked, m.options.LockKey)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+func (m *MigrationTool) acquireMySQLLock(tx *gorm.DB, lockName string) error {
+	timeoutSeconds := int64(m.options.LockTimeout / time.Second)
+	if m.options.LockTimeout <= 0 {
+		timeoutSeconds = -1 // GET_LOCK treats a negative timeout as "wait forever"
+	}
+
+	var acquired *int64
+	if err := tx.Raw("SELECT GET_LOCK(?, ?)", lockName, timeoutSeconds).Scan(&acquired).Error; err != nil {
+		return err
+	}
+	if acquired == nil || *acquired != 1 {
+		return fmt.Errorf("%w: mysql advisory lock %s", ErrMigrationLocked, lockName)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package deadigations
+
+import "time"
+
+// Options configures a MigrationTool. Use the With* functions together with
+// NewMigrationTool or NewMigrationToolWithDialector to override the defaults.
+type Options struct {
+	TableName                 string
+	IDColumnName              string
+	IDColumnSize              int
+	UseTransaction            bool
+	ValidateUnknownMigrations bool
+	AllowDrift                bool
+	LockTimeout               time.Duration
+	LockKey                   int64
+	Sources                   []Source
+}
+
+// Option mutates an Options value. It is applied in order, so later options
+// win when they touch the same field.
+type Option func(*Options)
+
+func defaultOptions() *Options {
+	return &Options{
+		TableName:                 "migrations",
+		IDColumnName:              "id",
+		IDColumnSize:              255,
+		UseTransaction:            true,
+		ValidateUnknownMigrations: true,
+		AllowDrift:                false,
+		LockTimeout:               0,
+		LockKey:                   defaultLockKey,
+	}
+}
+
+// WithLockTimeout bounds how long MigrateUp/MigrateDown wait to acquire the
+// cluster-wide migration lock before giving up with ErrMigrationLocked. Zero
+// (the default) waits indefinitely.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(o *Options) { o.LockTimeout = timeout }
+}
+
+// WithLockKey overrides the advisory lock key/name used to serialize
+// MigrateUp/MigrateDown across replicas. Change this if multiple,
+// independently-migrated apps share a database.
+func WithLockKey(key int64) Option {
+	return func(o *Options) { o.LockKey = key }
+}
+
+// WithTableName overrides the table used to track applied migrations.
+func WithTableName(name string) Option {
+	return func(o *Options) { o.TableName = name }
+}
+
+// WithIDColumnSize overrides the size of the migrations table's ID column.
+func WithIDColumnSize(size int) Option {
+	return func(o *Options) { o.IDColumnSize = size }
+}
+
+// WithUseTransaction toggles whether each migration runs inside a transaction.
+func WithUseTransaction(enabled bool) Option {
+	return func(o *Options) { o.UseTransaction = enabled }
+}
+
+// WithValidateUnknownMigrations toggles whether applied migration IDs that
+// are no longer registered cause MigrateUp to fail.
+func WithValidateUnknownMigrations(enabled bool) Option {
+	return func(o *Options) { o.ValidateUnknownMigrations = enabled }
+}
+
+// WithAllowDrift disables the checksum drift check performed on MigrateUp,
+// letting a previously-applied migration's body change without failing.
+func WithAllowDrift(enabled bool) Option {
+	return func(o *Options) { o.AllowDrift = enabled }
+}
+
+// WithSource adds a Source of migrations (e.g. DirSource or FSSource)
+// alongside any Go migrations registered via RegisterMigration. Sources are
+// read in the order they're added.
+func WithSource(source Source) Option {
+	return func(o *Options) { o.Sources = append(o.Sources, source) }
+}
@@ -0,0 +1,200 @@
+package deadigations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Source lets migrations live outside Go source files - e.g. as plain SQL
+// scripts on disk or embedded with go:embed - instead of requiring a
+// RegisterMigration call in an init(). MigrationTool reads every configured
+// Source alongside whatever has been registered directly.
+type Source interface {
+	// List returns every migration the source knows about, ready to run.
+	List() []Migration
+	// Read returns the raw up/down SQL for the migration with the given ID.
+	Read(id string) (up, down string, err error)
+}
+
+// sqlMigrationPattern matches the goose/sql-migrate file naming convention:
+// a numeric ID, a name, and an up/down suffix.
+var sqlMigrationPattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type fsSource struct {
+	fsys fs.FS
+}
+
+// DirSource builds a Source that scans path on disk for
+// NNNNNNNN_name.up.sql / NNNNNNNN_name.down.sql pairs.
+func DirSource(path string) Source {
+	return &fsSource{fsys: os.DirFS(path)}
+}
+
+// FSSource builds a Source that scans fsys for
+// NNNNNNNN_name.up.sql / NNNNNNNN_name.down.sql pairs. It's intended for use
+// with go:embed so SQL migrations can ship inside a single binary.
+func FSSource(fsys fs.FS) Source {
+	return &fsSource{fsys: fsys}
+}
+
+func (s *fsSource) List() []Migration {
+	migrations, err := scanSQLMigrations(s.fsys)
+	if err != nil {
+		log.Fatalf("Failed to scan SQL migrations: %v", err)
+	}
+	return migrations
+}
+
+func (s *fsSource) Read(id string) (string, string, error) {
+	return readSQLPair(s.fsys, id)
+}
+
+func scanSQLMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	type sqlPair struct {
+		up, down string
+	}
+	pairs := make(map[string]*sqlPair)
+	ids := make([]string, 0)
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches := sqlMigrationPattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+
+		id := matches[1]
+		pair, ok := pairs[id]
+		if !ok {
+			pair = &sqlPair{}
+			pairs[id] = pair
+			ids = append(ids, id)
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		switch matches[3] {
+		case "up":
+			pair.up = string(content)
+		case "down":
+			pair.down = string(content)
+		}
+	}
+
+	sort.Strings(ids)
+
+	migrations := make([]Migration, 0, len(ids))
+	for _, id := range ids {
+		pair := pairs[id]
+		upSQL, downSQL := pair.up, pair.down
+		migrations = append(migrations, Migration{
+			ID:          id,
+			Description: "SQL migration",
+			Checksum:    sqlChecksum(upSQL, downSQL),
+			Migrate: func(tx *gorm.DB) error {
+				return tx.Exec(upSQL).Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Exec(downSQL).Error
+			},
+		})
+	}
+	return migrations, nil
+}
+
+// sqlChecksum hashes a SQL migration's up/down bodies directly. Every SQL
+// migration shares the same Migrate/Rollback func literal (the closures
+// above), so computeChecksum's fallback - which derives a checksum from
+// where Migrate is defined - would compute an identical value for all of
+// them regardless of what the .sql files actually contain.
+func sqlChecksum(upSQL, downSQL string) string {
+	sum := sha256.Sum256([]byte(upSQL + "\x00" + downSQL))
+	return hex.EncodeToString(sum[:])
+}
+
+func readSQLPair(fsys fs.FS, id string) (string, string, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return "", "", err
+	}
+
+	var up, down string
+	found := false
+	for _, entry := range entries {
+		matches := sqlMigrationPattern.FindStringSubmatch(entry.Name())
+		if matches == nil || matches[1] != id {
+			continue
+		}
+		found = true
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return "", "", err
+		}
+		switch matches[3] {
+		case "up":
+			up = string(content)
+		case "down":
+			down = string(content)
+		}
+	}
+
+	if !found {
+		return "", "", fmt.Errorf("no SQL migration found with ID %s", id)
+	}
+	return up, down, nil
+}
+
+// allMigrations returns every Go-registered migration together with every
+// migration listed by the tool's configured Sources.
+func (m *MigrationTool) allMigrations() []Migration {
+	migrations := make([]Migration, 0, len(registeredMigrations))
+	migrations = append(migrations, registeredMigrations...)
+	for _, source := range m.options.Sources {
+		migrations = append(migrations, source.List()...)
+	}
+	return migrations
+}
+
+// CreateSQLMigrationFile writes a NNNNNNNN_name.up.sql / .down.sql pair into
+// ./migrator/migrations, ready for a DirSource or FSSource to pick up.
+func (m *MigrationTool) CreateSQLMigrationFile(name string) error {
+	timestamp := time.Now().Format("20060102150405") // YYYYMMDDHHMMSS
+	slug := strings.Replace(name, " ", "_", -1)
+
+	if err := os.MkdirAll("./migrator/migrations", os.ModePerm); err != nil {
+		return err
+	}
+
+	upPath := fmt.Sprintf("./migrator/migrations/%s_%s.up.sql", timestamp, slug)
+	downPath := fmt.Sprintf("./migrator/migrations/%s_%s.down.sql", timestamp, slug)
+
+	if err := os.WriteFile(upPath, []byte("-- Add up migration SQL here\n"), 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(downPath, []byte("-- Add down migration SQL here\n"), 0644); err != nil {
+		return err
+	}
+
+	log.Printf("SQL migration files created: %s, %s", upPath, downPath)
+	return nil
+}
@@ -0,0 +1,177 @@
+package deadigations
+
+import (
+	"log"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+)
+
+// MigrateDownTo rolls back every applied migration newer than id, leaving id
+// itself applied.
+func (m *MigrationTool) MigrateDownTo(id string) error {
+	return m.withLock(func() error {
+		return m.migrateDownTo(id)
+	})
+}
+
+func (m *MigrationTool) migrateDownTo(id string) error {
+	if err := m.ensureHistoryTable(); err != nil {
+		return err
+	}
+
+	migrator := gormigrate.New(m.db, m.gormigrateOptions(), m.buildGormigrateMigrations())
+	if err := migrator.RollbackTo(id); err != nil {
+		return err
+	}
+	log.Printf("Rolled back to migration %s successfully!", id)
+	return nil
+}
+
+// MigrateSteps applies n pending migrations when n is positive, or rolls
+// back the last -n applied migrations when n is negative.
+func (m *MigrationTool) MigrateSteps(n int) error {
+	if n == 0 {
+		return nil
+	}
+	return m.withLock(func() error {
+		return m.migrateSteps(n)
+	})
+}
+
+func (m *MigrationTool) migrateSteps(n int) error {
+	if err := m.ensureHistoryTable(); err != nil {
+		return err
+	}
+
+	if n > 0 {
+		return m.migrateStepsUp(n)
+	}
+	return m.migrateStepsDown(-n)
+}
+
+func (m *MigrationTool) migrateStepsUp(n int) error {
+	// The migrations table doesn't exist yet on a fresh database - gormigrate
+	// creates it below - so there's nothing to add a checksum column to or
+	// read applied IDs from until after that first run.
+	applied := make(map[string]bool)
+	if m.hasMigrationsTable() {
+		if err := m.ensureChecksumColumn(); err != nil {
+			return err
+		}
+
+		var err error
+		applied, err = m.appliedIDs()
+		if err != nil {
+			return err
+		}
+	}
+
+	var targetID string
+	pendingCount := 0
+	for _, migration := range m.allMigrations() {
+		if applied[migration.ID] {
+			continue
+		}
+		pendingCount++
+		targetID = migration.ID
+		if pendingCount == n {
+			break
+		}
+	}
+	if pendingCount == 0 {
+		log.Println("No pending migrations to apply")
+		return nil
+	}
+
+	// gormigrate needs the full migration list, not just the pending subset:
+	// with ValidateUnknownMigrations on, it treats any already-applied ID
+	// missing from the list as unknown-past-migration drift. MigrateTo bounds
+	// how far it actually runs instead.
+	migrator := gormigrate.New(m.db, m.gormigrateOptions(), m.buildGormigrateMigrations())
+	if err := migrator.MigrateTo(targetID); err != nil {
+		return err
+	}
+	if err := m.ensureChecksumColumn(); err != nil {
+		return err
+	}
+	if err := m.recordNewChecksums(applied); err != nil {
+		return err
+	}
+	log.Printf("Applied %d migration(s) successfully!", pendingCount)
+	return nil
+}
+
+func (m *MigrationTool) migrateStepsDown(n int) error {
+	if !m.hasMigrationsTable() {
+		log.Println("No applied migrations to roll back")
+		return nil
+	}
+
+	applied, err := m.appliedIDs()
+	if err != nil {
+		return err
+	}
+	if n > len(applied) {
+		log.Printf("Only %d migration(s) applied, rolling back all of them instead of %d", len(applied), n)
+		n = len(applied)
+	}
+	if n == 0 {
+		log.Println("No applied migrations to roll back")
+		return nil
+	}
+
+	migrator := gormigrate.New(m.db, m.gormigrateOptions(), m.buildGormigrateMigrations())
+	rolledBack := 0
+	for ; rolledBack < n; rolledBack++ {
+		if err := migrator.RollbackLast(); err != nil {
+			return err
+		}
+	}
+	log.Printf("Rolled back %d migration(s) successfully!", rolledBack)
+	return nil
+}
+
+// Redo rolls back the last applied migration and re-applies it.
+func (m *MigrationTool) Redo() error {
+	return m.withLock(m.redo)
+}
+
+func (m *MigrationTool) redo() error {
+	if err := m.ensureHistoryTable(); err != nil {
+		return err
+	}
+	if err := m.ensureChecksumColumn(); err != nil {
+		return err
+	}
+
+	before, err := m.appliedIDs()
+	if err != nil {
+		return err
+	}
+
+	migrator := gormigrate.New(m.db, m.gormigrateOptions(), m.buildGormigrateMigrations())
+	if err := migrator.RollbackLast(); err != nil {
+		return err
+	}
+
+	preApplied, err := m.appliedIDs()
+	if err != nil {
+		return err
+	}
+	// MigrateTo stops at the migration RollbackLast just undid, so pending
+	// migrations listed after it stay pending.
+	var redoneID string
+	for id := range before {
+		if !preApplied[id] {
+			redoneID = id
+		}
+	}
+	if err := migrator.MigrateTo(redoneID); err != nil {
+		return err
+	}
+	if err := m.recordNewChecksums(preApplied); err != nil {
+		return err
+	}
+	log.Println("Redid last migration successfully!")
+	return nil
+}